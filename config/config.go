@@ -2,8 +2,11 @@ package config
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/gruntwork-io/terragrunt/errors"
@@ -16,15 +19,102 @@ import (
 const DefaultTerragruntConfigPath = "terraform.tfvars"
 const OldTerragruntConfigPath = ".terragrunt"
 
+// Terragrunt also accepts its configuration as JSON, mirroring the way Terraform itself accepts *.tf.json
+// alongside *.tf. These are the filenames we look for, in order of precedence, when terraform.tfvars is not
+// present.
+const DefaultTerragruntJSONConfigPath = "terragrunt.json"
+const TerraformTfvarsJSONConfigPath = "terraform.tfvars.json"
+
 // TerragruntConfig represents a parsed and expanded configuration
 type TerragruntConfig struct {
 	Terraform    *TerraformConfig
 	RemoteState  *remote.RemoteState
 	Dependencies *ModuleDependencies
+
+	// IncludedConfigPaths is the resolved include chain this config was merged from, ordered from the immediate
+	// parent to the root of the chain. It's populated by parseConfigString purely for debugging and for commands,
+	// like `terragrunt config show`, that want to annotate the merged config with where each field came from.
+	IncludedConfigPaths []string
+
+	// Provenance records, for each field that has a value (see FieldProvenance for the field names), the path of the
+	// config file in the include chain that set it, as that path was passed to or resolved by ParseConfigFile. It's
+	// what a `terragrunt config show` command would use to annotate the merged config with per-field "came from"
+	// information, rather than just the flat IncludedConfigPaths chain.
+	Provenance []FieldProvenance
 }
 
 func (conf *TerragruntConfig) String() string {
-	return fmt.Sprintf("TerragruntConfig{Terraform = %v, RemoteState = %v, Dependencies = %v}", conf.Terraform, conf.RemoteState, conf.Dependencies)
+	return fmt.Sprintf("TerragruntConfig{Terraform = %v, RemoteState = %v, Dependencies = %v, IncludedConfigPaths = %v, Provenance = %v}", conf.Terraform, conf.RemoteState, conf.Dependencies, conf.IncludedConfigPaths, conf.Provenance)
+}
+
+// FieldProvenance records that a TerragruntConfig field's final, merged value came from the config file at
+// SourcePath. Field names a TerragruntConfig field, e.g. "Terraform.Source", "Dependencies", or, since RemoteState is
+// merged key by key, "RemoteState.Backend" and "RemoteState.Config.<key>".
+type FieldProvenance struct {
+	Field      string
+	SourcePath string
+}
+
+// ProvenanceFor returns the SourcePath recorded for field, or "" if nothing set it. Intended for a `terragrunt
+// config show` command to annotate each field of the merged config with where it came from.
+func (conf *TerragruntConfig) ProvenanceFor(field string) string {
+	for _, p := range conf.Provenance {
+		if p.Field == field {
+			return p.SourcePath
+		}
+	}
+	return ""
+}
+
+// FormatWithProvenance renders conf with a "<- came from <path>" annotation after Terraform, RemoteState.Backend, each
+// RemoteState.Config key and Dependencies, wherever those have recorded provenance. Provenance recorded for
+// Terraform.Source and Terraform.ExtraArgs is not shown; the Terraform annotation names the file that first set the
+// Terraform block. This is the primitive a `terragrunt config show` command would call to print the merged config
+// alongside where each field came from; this package has no CLI surface of its own to wire that command into.
+func (conf *TerragruntConfig) FormatWithProvenance() string {
+	annotate := func(field string) string {
+		if source := conf.ProvenanceFor(field); source != "" {
+			return fmt.Sprintf(" <- came from %s", source)
+		}
+		return ""
+	}
+
+	remoteState := fmt.Sprintf("%v", conf.RemoteState)
+	if conf.RemoteState != nil {
+		remoteState = fmt.Sprintf(
+			"RemoteState{Backend = %v%s, Config = %s}",
+			conf.RemoteState.Backend, annotate("RemoteState.Backend"),
+			conf.formatRemoteStateConfigWithProvenance(),
+		)
+	}
+
+	return fmt.Sprintf(
+		"TerragruntConfig{\n  Terraform = %v%s\n  RemoteState = %s\n  Dependencies = %v%s\n}",
+		conf.Terraform, annotate("Terraform"),
+		remoteState,
+		conf.Dependencies, annotate("Dependencies"),
+	)
+}
+
+// formatRemoteStateConfigWithProvenance renders conf.RemoteState.Config as "{key = value <- came from path, ...}",
+// one entry per key, sorted for deterministic output since map iteration order isn't.
+func (conf *TerragruntConfig) formatRemoteStateConfigWithProvenance() string {
+	keys := make([]string, 0, len(conf.RemoteState.Config))
+	for key := range conf.RemoteState.Config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	entries := make([]string, 0, len(keys))
+	for _, key := range keys {
+		entry := fmt.Sprintf("%s = %v", key, conf.RemoteState.Config[key])
+		if source := conf.ProvenanceFor(fmt.Sprintf("RemoteState.Config.%s", key)); source != "" {
+			entry += fmt.Sprintf(" <- came from %s", source)
+		}
+		entries = append(entries, entry)
+	}
+
+	return fmt.Sprintf("{%s}", strings.Join(entries, ", "))
 }
 
 // terragruntConfigFile represents the configuration supported in a Terragrunt configuration file (i.e.
@@ -53,20 +143,35 @@ type IncludeConfig struct {
 	Path string `hcl:"path"`
 }
 
+// DependenciesMergeAppend, set as `dependencies { merge = "append" }` in a child config, appends the child's
+// Dependencies.Paths to the included config's instead of replacing them outright.
+const DependenciesMergeAppend = "append"
+
+// DependenciesMergeReplace is the default merge mode: the child's Dependencies.Paths replace the included config's.
+const DependenciesMergeReplace = "replace"
+
 // ModuleDependencies represents the paths to other Terraform modules that must be applied before the current module
 // can be applied
 type ModuleDependencies struct {
 	Paths []string `hcl:"paths"`
+
+	// Merge selects how this block combines with one inherited via include: DependenciesMergeReplace (the
+	// default) or DependenciesMergeAppend.
+	Merge string `hcl:"merge,omitempty"`
 }
 
 func (deps *ModuleDependencies) String() string {
-	return fmt.Sprintf("ModuleDependencies{Paths = %v}", deps.Paths)
+	return fmt.Sprintf("ModuleDependencies{Paths = %v, Merge = %v}", deps.Paths, deps.Merge)
 }
 
 // TerraformConfig specifies where to find the Terraform configuration files
 type TerraformConfig struct {
 	ExtraArgs []TerraformExtraArguments `hcl:"extra_arguments"`
 	Source    string                    `hcl:"source"`
+
+	// RemoveExtraArguments lists the names of extra_arguments blocks to drop from an included config, so a child
+	// can opt out of an inherited entry instead of only being able to override it by name.
+	RemoveExtraArguments []string `hcl:"remove_extra_arguments,omitempty"`
 }
 
 func (conf *TerraformConfig) String() string {
@@ -86,36 +191,295 @@ func (conf *TerraformExtraArguments) String() string {
 	return fmt.Sprintf("TerraformArguments{Name = %s, Arguments = %v, Commands = %v}", conf.Name, conf.Arguments, conf.Commands)
 }
 
+// ConfigFileDetector knows how to recognize Terragrunt config in a given working directory. Terragrunt ships a
+// detector for each config format it understands (see DefaultConfigFileDetectors), and FindConfigFilesInPath tries
+// each one, in order, against every directory it walks.
+type ConfigFileDetector interface {
+	// DefaultConfigPath returns the path this detector considers the canonical Terragrunt config location inside
+	// workingDir, whether or not a file actually exists there yet.
+	DefaultConfigPath(workingDir string) string
+
+	// IsConfigFile returns true if path is recognized by this detector as valid Terragrunt config. For file-based
+	// detectors, this means the file exists and, for the tfvars/JSON format, contains a terragrunt = { ... } block (a
+	// .terragrunt file is recognized by its name alone); for directory-based detectors (e.g. plain Terraform), path is
+	// itself the directory being tested.
+	IsConfigFile(path string) (bool, error)
+}
+
+// oldConfigFileDetector recognizes the deprecated .terragrunt file format.
+type oldConfigFileDetector struct{}
+
+func (detector *oldConfigFileDetector) DefaultConfigPath(workingDir string) string {
+	return util.JoinPath(workingDir, OldTerragruntConfigPath)
+}
+
+func (detector *oldConfigFileDetector) IsConfigFile(path string) (bool, error) {
+	return isOldTerragruntConfig(path) && util.FileExists(path), nil
+}
+
+// tfvarsConfigFileDetector recognizes the current terragrunt = { ... } block format, whether embedded in
+// terraform.tfvars or written as JSON in terraform.tfvars.json or terragrunt.json.
+type tfvarsConfigFileDetector struct{}
+
+func (detector *tfvarsConfigFileDetector) DefaultConfigPath(workingDir string) string {
+	tfvarsPath := util.JoinPath(workingDir, DefaultTerragruntConfigPath)
+	if util.FileExists(tfvarsPath) {
+		return tfvarsPath
+	}
+
+	// terraform.tfvars.json is a standard Terraform-autoloaded variables file that has nothing to do with
+	// Terragrunt, so it (and terragrunt.json) are only consulted once terraform.tfvars is confirmed absent, and
+	// only if they actually contain a terragrunt = { ... } block, not merely because a file exists at that path.
+	for _, jsonConfigPath := range []string{DefaultTerragruntJSONConfigPath, TerraformTfvarsJSONConfigPath} {
+		path := util.JoinPath(workingDir, jsonConfigPath)
+		if isConfig, err := isNewTerragruntConfig(path); err == nil && isConfig {
+			return path
+		}
+	}
+
+	return tfvarsPath
+}
+
+func (detector *tfvarsConfigFileDetector) IsConfigFile(path string) (bool, error) {
+	if !util.FileExists(path) {
+		return false, nil
+	}
+
+	return isNewTerragruntConfig(path)
+}
+
+// PlainTerraformConfigFileDetector recognizes any directory containing plain Terraform *.tf files as a valid target,
+// even though it has no Terragrunt config of its own. It is not included in DefaultConfigFileDetectors; callers opt
+// into it explicitly so that modules without a terragrunt block can be treated as nodes in the run-all DAG.
+type PlainTerraformConfigFileDetector struct{}
+
+// DefaultConfigPath returns workingDir itself: a plain Terraform module has no single config file, so the directory
+// is the config path passed through the rest of this package (see ParseConfigFile).
+func (detector *PlainTerraformConfigFileDetector) DefaultConfigPath(workingDir string) string {
+	return workingDir
+}
+
+func (detector *PlainTerraformConfigFileDetector) IsConfigFile(path string) (bool, error) {
+	info, err := os.Stat(path)
+	if err != nil || !info.IsDir() {
+		return false, nil
+	}
+
+	// Deliberately not filepath.Glob: it treats glob metacharacters (e.g. an unmatched '[') anywhere in path, not
+	// just the trailing "*.tf" pattern, as pattern syntax, so an oddly-named directory elsewhere in the tree would
+	// turn into an ErrBadPattern that aborts the entire FindConfigFilesInPath walk.
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return false, errors.WithStackTrace(err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".tf" {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// DefaultConfigFileDetectors returns the detectors Terragrunt uses unless a caller opts into additional ones (e.g.
+// PlainTerraformConfigFileDetector) via options.TerragruntOptions.
+func DefaultConfigFileDetectors() []ConfigFileDetector {
+	return []ConfigFileDetector{&oldConfigFileDetector{}, &tfvarsConfigFileDetector{}}
+}
+
+// ConfigFileDetectorsForOptions returns the detector list FindConfigFilesInPath should use for the given
+// terragruntOptions: DefaultConfigFileDetectors, plus PlainTerraformConfigFileDetector when the caller has set
+// terragruntOptions.IncludePlainTerraformModules. A caller passes the result on to FindConfigFilesInPath.
+func ConfigFileDetectorsForOptions(terragruntOptions *options.TerragruntOptions) []ConfigFileDetector {
+	detectors := DefaultConfigFileDetectors()
+	if terragruntOptions != nil && terragruntOptions.IncludePlainTerraformModules {
+		detectors = append(detectors, &PlainTerraformConfigFileDetector{})
+	}
+	return detectors
+}
+
 // Return the default path to use for the Terragrunt configuration file. The reason this is a method rather than a
-// constant is that older versions of Terragrunt stored configuration in a different file. This method returns the
-// path to the old configuration format if such a file exists and the new format otherwise.
+// constant is that older versions of Terragrunt stored configuration in a different file, and newer versions also
+// allow the config to be written as JSON, so we have several candidate paths to check.
 func DefaultConfigPath(workingDir string) string {
-	path := util.JoinPath(workingDir, OldTerragruntConfigPath)
-	if util.FileExists(path) {
-		return path
+	return defaultConfigPathForDetectors(workingDir, DefaultConfigFileDetectors())
+}
+
+func defaultConfigPathForDetectors(workingDir string, detectors []ConfigFileDetector) string {
+	var lastCandidate string
+
+	for _, detector := range detectors {
+		candidate := detector.DefaultConfigPath(workingDir)
+		if util.FileExists(candidate) {
+			return candidate
+		}
+		lastCandidate = candidate
+	}
+
+	return lastCandidate
+}
+
+// DiscoveryFilterConfigPath is the name of the optional file, placed at the root path passed to
+// FindConfigFilesInPath, that scopes which subtrees are walked and returned. If the file isn't present, discovery
+// behaves exactly as before: every subtree is walked and every Terragrunt config found is returned.
+const DiscoveryFilterConfigPath = ".terragrunt-discovery.hcl"
+
+// discoveryFilterFile represents the contents of a DiscoveryFilterConfigPath file: an ordered list of include/exclude
+// rules.
+type discoveryFilterFile struct {
+	Rules []discoveryRule `hcl:"rule"`
+}
+
+// discoveryRule is a single include/exclude rule. Prefix is matched against the path of a directory or config file
+// relative to the root passed to FindConfigFilesInPath; Name is a regexp that matches if it matches either that
+// relative path or its basename. If both are set, both must match. A rule with
+// neither set matches everything, so it can be used as a catch-all at the end of the list.
+type discoveryRule struct {
+	Prefix  string `hcl:"prefix,omitempty"`
+	Name    string `hcl:"name,omitempty"`
+	Exclude bool   `hcl:"exclude,omitempty"`
+}
+
+// discoveryFilter is the compiled, ready-to-evaluate form of a discoveryFilterFile.
+type discoveryFilter struct {
+	rules []compiledDiscoveryRule
+}
+
+type compiledDiscoveryRule struct {
+	prefix  string
+	name    *regexp.Regexp
+	exclude bool
+}
+
+// loadDiscoveryFilter reads and compiles the DiscoveryFilterConfigPath file at the given root, if one exists. It
+// returns a nil filter, and no error, when the file is absent.
+func loadDiscoveryFilter(rootPath string) (*discoveryFilter, error) {
+	path := util.JoinPath(rootPath, DiscoveryFilterConfigPath)
+	if !util.FileExists(path) {
+		return nil, nil
+	}
+
+	configString, err := util.ReadFileAsString(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &discoveryFilterFile{}
+	if err := hcl.Decode(file, configString); err != nil {
+		return nil, errors.WithStackTrace(err)
 	}
-	return util.JoinPath(workingDir, DefaultTerragruntConfigPath)
+
+	return compileDiscoveryFilter(file)
 }
 
-// Returns a list of all Terragrunt config files in the given path or any subfolder of the path. A file is a Terragrunt
-// config file if it has a name as returned by the DefaultConfigPath method and contains Terragrunt config contents
-// as returned by the IsTerragruntConfigFile method.
-func FindConfigFilesInPath(rootPath string) ([]string, error) {
+// compileDiscoveryFilter turns the raw rules parsed from a discoveryFilterFile into a discoveryFilter, compiling
+// each rule's Name into a regexp up front so FindConfigFilesInPath doesn't recompile it on every Walk callback.
+func compileDiscoveryFilter(file *discoveryFilterFile) (*discoveryFilter, error) {
+	rules := make([]compiledDiscoveryRule, 0, len(file.Rules))
+
+	for _, rule := range file.Rules {
+		compiled := compiledDiscoveryRule{prefix: rule.Prefix, exclude: rule.Exclude}
+
+		if rule.Name != "" {
+			name, err := regexp.Compile(rule.Name)
+			if err != nil {
+				return nil, errors.WithStackTrace(err)
+			}
+			compiled.name = name
+		}
+
+		rules = append(rules, compiled)
+	}
+
+	return &discoveryFilter{rules: rules}, nil
+}
+
+// included returns whether relPath, a directory or config path relative to the discovery root, should be walked or
+// returned. Rules are evaluated in order and the first match wins; if no rule matches, or the filter is nil because
+// no DiscoveryFilterConfigPath file was found, the default is to include.
+func (filter *discoveryFilter) included(relPath string) bool {
+	if filter == nil {
+		return true
+	}
+
+	base := filepath.Base(relPath)
+
+	for _, rule := range filter.rules {
+		if rule.prefix != "" && !hasPathPrefix(relPath, rule.prefix) {
+			continue
+		}
+		if rule.name != nil && !rule.name.MatchString(base) && !rule.name.MatchString(relPath) {
+			continue
+		}
+		return !rule.exclude
+	}
+
+	return true
+}
+
+// hasPathPrefix reports whether path starts with prefix at a path-segment boundary, so a rule `prefix = "live"`
+// matches "live/foo" but not "live-old/foo" or "livestock/bar", unlike a raw strings.HasPrefix check.
+func hasPathPrefix(path string, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, string(filepath.Separator))
+	if path == prefix {
+		return true
+	}
+	return strings.HasPrefix(path, prefix+string(filepath.Separator))
+}
+
+// Returns a list of all Terragrunt config files in the given path or any subfolder of the path. A directory yields a
+// config file if any of the given detectors recognizes one there; detectors are tried in order and the first match
+// wins. If no detectors are given, DefaultConfigFileDetectors is used, which preserves the historical behavior of
+// looking only for a terraform.tfvars / .terragrunt style config.
+//
+// If rootPath contains a DiscoveryFilterConfigPath file, its include/exclude rules are used to prune which
+// directories get walked and which discovered configs get returned.
+func FindConfigFilesInPath(rootPath string, detectors ...ConfigFileDetector) ([]string, error) {
+	if len(detectors) == 0 {
+		detectors = DefaultConfigFileDetectors()
+	}
+
 	configFiles := []string{}
 
-	err := filepath.Walk(rootPath, func(path string, info os.FileInfo, err error) error {
+	filter, err := loadDiscoveryFilter(rootPath)
+	if err != nil {
+		return nil, err
+	}
+
+	err = filepath.Walk(rootPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 
 		if info.IsDir() {
-			configPath := DefaultConfigPath(path)
-			isTerragruntConfig, err := IsTerragruntConfigFile(configPath)
+			relPath, err := filepath.Rel(rootPath, path)
 			if err != nil {
 				return err
 			}
-			if isTerragruntConfig {
-				configFiles = append(configFiles, configPath)
+
+			if relPath != "." && !filter.included(relPath) {
+				return filepath.SkipDir
+			}
+
+			for _, detector := range detectors {
+				configPath := detector.DefaultConfigPath(path)
+				isTerragruntConfig, err := detector.IsConfigFile(configPath)
+				if err != nil {
+					return err
+				}
+				if !isTerragruntConfig {
+					continue
+				}
+
+				configRelPath, err := filepath.Rel(rootPath, configPath)
+				if err != nil {
+					return err
+				}
+				if filter.included(configRelPath) {
+					configFiles = append(configFiles, configPath)
+				}
+				break
 			}
 		}
 
@@ -130,17 +494,22 @@ func FindConfigFilesInPath(rootPath string) ([]string, error) {
 //
 // 1. The file exists
 // 2. It is a .terragrunt file, which is the old Terragrunt-specific file format
-// 3. The file contains HCL contents with a terragrunt = { ... } block
+// 3. The file contains HCL or JSON contents with a terragrunt = { ... } block
+//
+// This checks against DefaultConfigFileDetectors only; it does not consider opt-in detectors like
+// PlainTerraformConfigFileDetector.
 func IsTerragruntConfigFile(path string) (bool, error) {
-	if !util.FileExists(path) {
-		return false, nil
-	}
-
-	if isOldTerragruntConfig(path) {
-		return true, nil
+	for _, detector := range DefaultConfigFileDetectors() {
+		isConfig, err := detector.IsConfigFile(path)
+		if err != nil {
+			return false, err
+		}
+		if isConfig {
+			return true, nil
+		}
 	}
 
-	return isNewTerragruntConfig(path)
+	return false, nil
 }
 
 // Returns true if the given path points to an old Terragrunt config file
@@ -158,7 +527,8 @@ func isNewTerragruntConfig(path string) (bool, error) {
 	return containsTerragruntBlock(configContents), nil
 }
 
-// Returns true if the given string contains valid HCL with a terragrunt = { ... } block
+// Returns true if the given string contains valid HCL or JSON with a terragrunt = { ... } block. hcl.Decode detects
+// JSON input automatically, so this works unchanged for both terraform.tfvars.json and terragrunt.json.
 func containsTerragruntBlock(configString string) bool {
 	terragruntConfig := &tfvarsFileWithTerragruntConfig{}
 	if err := hcl.Decode(terragruntConfig, configString); err != nil {
@@ -176,16 +546,36 @@ func ReadTerragruntConfig(terragruntOptions *options.TerragruntOptions) (*Terrag
 // Parse the Terragrunt config file at the given path. If the include parameter is not nil, then treat this as a config
 // included in some other config file when resolving relative paths.
 func ParseConfigFile(configPath string, terragruntOptions *options.TerragruntOptions, include *IncludeConfig) (*TerragruntConfig, error) {
+	return parseConfigFile(configPath, terragruntOptions, include, map[string]bool{}, 0)
+}
+
+// parseConfigFile is the recursive worker behind ParseConfigFile. visited tracks the absolute paths of every config
+// already parsed in the current include chain, so a cycle can be detected instead of recursing forever; depth counts
+// how many includes deep the current call is, so the chain can be cut off at MaxIncludeDepth.
+func parseConfigFile(configPath string, terragruntOptions *options.TerragruntOptions, include *IncludeConfig, visited map[string]bool, depth int) (*TerragruntConfig, error) {
+	if info, err := os.Stat(configPath); err == nil && info.IsDir() {
+		// A directory here means configPath was produced by PlainTerraformConfigFileDetector: there is no
+		// Terragrunt config to parse, so synthesize an empty one and let the directory's *.tf files stand on
+		// their own.
+		return newPlainTerraformConfig(), nil
+	}
+
 	if isOldTerragruntConfig(configPath) {
 		terragruntOptions.Logger.Printf("DEPRECATION WARNING: Found deprecated config file format %s. This old config format will not be supported in the future. Please move your config files into a %s file.", configPath, DefaultTerragruntConfigPath)
 	}
 
+	absConfigPath, err := filepath.Abs(configPath)
+	if err != nil {
+		return nil, errors.WithStackTrace(err)
+	}
+	visited[absConfigPath] = true
+
 	configString, err := util.ReadFileAsString(configPath)
 	if err != nil {
 		return nil, err
 	}
 
-	config, err := parseConfigString(configString, terragruntOptions, include, configPath)
+	config, err := parseConfigString(configString, terragruntOptions, include, configPath, visited, depth)
 	if err != nil {
 		return nil, err
 	}
@@ -194,7 +584,7 @@ func ParseConfigFile(configPath string, terragruntOptions *options.TerragruntOpt
 }
 
 // Parse the Terragrunt config contained in the given string.
-func parseConfigString(configString string, terragruntOptions *options.TerragruntOptions, include *IncludeConfig, configPath string) (*TerragruntConfig, error) {
+func parseConfigString(configString string, terragruntOptions *options.TerragruntOptions, include *IncludeConfig, configPath string, visited map[string]bool, depth int) (*TerragruntConfig, error) {
 	resolvedConfigString, err := ResolveTerragruntConfigString(configString, include, terragruntOptions)
 	if err != nil {
 		return nil, err
@@ -213,24 +603,27 @@ func parseConfigString(configString string, terragruntOptions *options.Terragrun
 		return nil, err
 	}
 
-	if include != nil && terragruntConfigFile.Include != nil {
-		return nil, errors.WithStackTrace(TooManyLevelsOfInheritance{
-			ConfigPath:             terragruntOptions.TerragruntConfigPath,
-			FirstLevelIncludePath:  include.Path,
-			SecondLevelIncludePath: terragruntConfigFile.Include.Path,
-		})
+	includedConfig, includedConfigPath, err := parseIncludedConfig(terragruntConfigFile.Include, terragruntOptions, configPath, visited, depth)
+	if err != nil {
+		return nil, err
 	}
 
-	includedConfig, err := parseIncludedConfig(terragruntConfigFile.Include, terragruntOptions)
+	merged, err := mergeConfigWithIncludedConfig(configPath, config, includedConfig, terragruntOptions)
 	if err != nil {
 		return nil, err
 	}
 
-	return mergeConfigWithIncludedConfig(config, includedConfig, terragruntOptions)
+	if includedConfigPath != "" {
+		merged.IncludedConfigPaths = append([]string{includedConfigPath}, merged.IncludedConfigPaths...)
+	}
+
+	return merged, nil
 }
 
 // Parse the given config string, read from the given config file, as a terragruntConfigFile struct. This method solely
-// converts the HCL syntax in the string to the terragruntConfigFile struct; it does not process any interpolations.
+// converts the HCL or JSON syntax in the string to the terragruntConfigFile struct; it does not process any
+// interpolations. hcl.Decode dispatches to hcl.Parse internally, which already auto-detects JSON input, so
+// terraform.tfvars.json and terragrunt.json decode into the same structs as their HCL equivalents with no extra work.
 func parseConfigStringAsTerragruntConfigFile(configString string, configPath string) (*terragruntConfigFile, error) {
 	if isOldTerragruntConfig(configPath) {
 		terragruntConfig := &terragruntConfigFile{}
@@ -249,33 +642,141 @@ func parseConfigStringAsTerragruntConfigFile(configString string, configPath str
 
 // Merge the given config with an included config. Anything specified in the current config will override the contents
 // of the included config. If the included config is nil, just return the current config.
-func mergeConfigWithIncludedConfig(config *TerragruntConfig, includedConfig *TerragruntConfig, terragruntOptions *options.TerragruntOptions) (*TerragruntConfig, error) {
+func mergeConfigWithIncludedConfig(configPath string, config *TerragruntConfig, includedConfig *TerragruntConfig, terragruntOptions *options.TerragruntOptions) (*TerragruntConfig, error) {
 	if includedConfig == nil {
+		config.Provenance = provenanceForLeaf(configPath, config)
 		return config, nil
 	}
 
-	if config.RemoteState != nil {
-		includedConfig.RemoteState = config.RemoteState
-	}
+	provenance := append([]FieldProvenance{}, includedConfig.Provenance...)
+
+	includedConfig.RemoteState = mergeRemoteState(includedConfig.RemoteState, config.RemoteState)
+	provenance = setRemoteStateProvenance(provenance, configPath, config.RemoteState)
 
 	if config.Terraform != nil {
 		if includedConfig.Terraform == nil {
 			includedConfig.Terraform = config.Terraform
+			provenance = setProvenance(provenance, "Terraform", configPath)
 		} else {
 			if config.Terraform.Source != "" {
 				includedConfig.Terraform.Source = config.Terraform.Source
+				provenance = setProvenance(provenance, "Terraform.Source", configPath)
 			}
+			// Remove inherited entries before merging in the child's own extra_arguments, so a child can both
+			// drop a parent's block and define its own block of the same name without the removal eating it.
+			removeExtraArgs(config.Terraform.RemoveExtraArguments, &includedConfig.Terraform.ExtraArgs)
 			mergeExtraArgs(terragruntOptions, config.Terraform.ExtraArgs, &includedConfig.Terraform.ExtraArgs)
+			if len(config.Terraform.ExtraArgs) > 0 {
+				provenance = setProvenance(provenance, "Terraform.ExtraArgs", configPath)
+			}
 		}
 	}
 
+	includedConfig.Dependencies = mergeDependencies(terragruntOptions, includedConfig.Dependencies, config.Dependencies)
 	if config.Dependencies != nil {
-		includedConfig.Dependencies = config.Dependencies
+		provenance = setProvenance(provenance, "Dependencies", configPath)
 	}
 
+	includedConfig.Provenance = provenance
+
 	return includedConfig, nil
 }
 
+// provenanceForLeaf tags every field config has a value for with configPath. Used when config has no include of its
+// own, so it's the root of its slice of the chain.
+func provenanceForLeaf(configPath string, config *TerragruntConfig) []FieldProvenance {
+	var provenance []FieldProvenance
+
+	provenance = setRemoteStateProvenance(provenance, configPath, config.RemoteState)
+	if config.Terraform != nil {
+		provenance = setProvenance(provenance, "Terraform", configPath)
+	}
+	if config.Dependencies != nil {
+		provenance = setProvenance(provenance, "Dependencies", configPath)
+	}
+
+	return provenance
+}
+
+// setRemoteStateProvenance tags remoteState's Backend and each of its Config keys as having come from configPath.
+// RemoteState is merged key by key (see mergeRemoteState), so unlike the other TerragruntConfig fields its
+// provenance has to be tracked at that same granularity: tagging the whole "RemoteState" field with configPath
+// whenever remoteState is non-nil would wrongly attribute values the merge actually left inherited from a parent
+// (e.g. remote_state.config.region) to this, the child's, config file.
+func setRemoteStateProvenance(provenance []FieldProvenance, configPath string, remoteState *remote.RemoteState) []FieldProvenance {
+	if remoteState == nil {
+		return provenance
+	}
+
+	if remoteState.Backend != "" {
+		provenance = setProvenance(provenance, "RemoteState.Backend", configPath)
+	}
+	for key := range remoteState.Config {
+		provenance = setProvenance(provenance, fmt.Sprintf("RemoteState.Config.%s", key), configPath)
+	}
+
+	return provenance
+}
+
+// setProvenance records that field's final value came from sourcePath, replacing any earlier entry for the same
+// field (a closer override always wins over one further up the include chain).
+func setProvenance(provenance []FieldProvenance, field string, sourcePath string) []FieldProvenance {
+	for i, p := range provenance {
+		if p.Field == field {
+			provenance[i].SourcePath = sourcePath
+			return provenance
+		}
+	}
+	return append(provenance, FieldProvenance{Field: field, SourcePath: sourcePath})
+}
+
+// Merge a child RemoteState on top of the parent's, field by field, so a child can override a single nested setting
+// (e.g. config.bucket) while inheriting the rest (e.g. config.region) instead of replacing the whole block.
+func mergeRemoteState(parentRemoteState *remote.RemoteState, childRemoteState *remote.RemoteState) *remote.RemoteState {
+	if childRemoteState == nil {
+		return parentRemoteState
+	}
+	if parentRemoteState == nil {
+		return childRemoteState
+	}
+
+	merged := &remote.RemoteState{Backend: parentRemoteState.Backend, Config: map[string]interface{}{}}
+	for key, value := range parentRemoteState.Config {
+		merged.Config[key] = value
+	}
+
+	if childRemoteState.Backend != "" {
+		merged.Backend = childRemoteState.Backend
+	}
+	for key, value := range childRemoteState.Config {
+		merged.Config[key] = value
+	}
+
+	return merged
+}
+
+// Merge a child's Dependencies on top of the parent's. The default, matching the historical behavior, is for the
+// child's Paths to replace the parent's outright; a child can instead set `dependencies { merge = "append" }` to
+// have its paths appended after the parent's.
+func mergeDependencies(terragruntOptions *options.TerragruntOptions, parentDependencies *ModuleDependencies, childDependencies *ModuleDependencies) *ModuleDependencies {
+	if childDependencies == nil {
+		return parentDependencies
+	}
+	if parentDependencies == nil {
+		return childDependencies
+	}
+
+	switch childDependencies.Merge {
+	case "", DependenciesMergeReplace:
+		return childDependencies
+	case DependenciesMergeAppend:
+		return &ModuleDependencies{Paths: append(append([]string{}, parentDependencies.Paths...), childDependencies.Paths...)}
+	default:
+		terragruntOptions.Logger.Printf("WARNING: unrecognized dependencies merge mode '%v'; falling back to '%s'", childDependencies.Merge, DependenciesMergeReplace)
+		return childDependencies
+	}
+}
+
 // Merge the extra arguments prioritizing those defined in the childExtraArgs
 func mergeExtraArgs(terragruntOptions *options.TerragruntOptions, childExtraArgs []TerraformExtraArguments, parentExtraArgs *[]TerraformExtraArguments) {
 	result := *parentExtraArgs
@@ -295,25 +796,81 @@ addExtra:
 	*parentExtraArgs = result
 }
 
-// Parse the config of the given include, if one is specified
-func parseIncludedConfig(includedConfig *IncludeConfig, terragruntOptions *options.TerragruntOptions) (*TerragruntConfig, error) {
+// Drop any parent extra_arguments blocks named in namesToRemove, so a child can opt out of an inherited entry instead
+// of only being able to override it by name.
+func removeExtraArgs(namesToRemove []string, parentExtraArgs *[]TerraformExtraArguments) {
+	if len(namesToRemove) == 0 {
+		return
+	}
+
+	result := make([]TerraformExtraArguments, 0, len(*parentExtraArgs))
+removeKept:
+	for _, arg := range *parentExtraArgs {
+		for _, name := range namesToRemove {
+			if arg.Name == name {
+				continue removeKept
+			}
+		}
+		result = append(result, arg)
+	}
+	*parentExtraArgs = result
+}
+
+// effectiveMaxIncludeDepth returns terragruntOptions.MaxIncludeDepth, falling back to
+// options.DefaultMaxIncludeDepth for a TerragruntOptions that wasn't built via options.NewTerragruntOptions and so
+// left MaxIncludeDepth at its zero value.
+func effectiveMaxIncludeDepth(terragruntOptions *options.TerragruntOptions) int {
+	if terragruntOptions.MaxIncludeDepth > 0 {
+		return terragruntOptions.MaxIncludeDepth
+	}
+	return options.DefaultMaxIncludeDepth
+}
+
+// Parse the config of the given include, if one is specified. configPath is the path of the config file that
+// declared this include: a relative include path is resolved against its directory, and error messages name it so
+// they point at the right file in a multi-level chain. Returns
+// the absolute path of the included config alongside its parsed TerragruntConfig, so the caller can record it in
+// TerragruntConfig.IncludedConfigPaths.
+func parseIncludedConfig(includedConfig *IncludeConfig, terragruntOptions *options.TerragruntOptions, configPath string, visited map[string]bool, depth int) (*TerragruntConfig, string, error) {
 	if includedConfig == nil {
-		return nil, nil
+		return nil, "", nil
 	}
 	if includedConfig.Path == "" {
-		return nil, errors.WithStackTrace(IncludedConfigMissingPath(terragruntOptions.TerragruntConfigPath))
+		return nil, "", errors.WithStackTrace(IncludedConfigMissingPath(configPath))
 	}
 
 	resolvedIncludePath, err := ResolveTerragruntConfigString(includedConfig.Path, nil, terragruntOptions)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	if !filepath.IsAbs(resolvedIncludePath) {
-		resolvedIncludePath = util.JoinPath(filepath.Dir(terragruntOptions.TerragruntConfigPath), resolvedIncludePath)
+		resolvedIncludePath = util.JoinPath(filepath.Dir(configPath), resolvedIncludePath)
+	}
+
+	absIncludePath, err := filepath.Abs(resolvedIncludePath)
+	if err != nil {
+		return nil, "", errors.WithStackTrace(err)
+	}
+
+	if visited[absIncludePath] {
+		return nil, "", errors.WithStackTrace(CircularInclude{ConfigPath: configPath, IncludePath: absIncludePath})
 	}
 
-	return ParseConfigFile(resolvedIncludePath, terragruntOptions, includedConfig)
+	maxIncludeDepth := effectiveMaxIncludeDepth(terragruntOptions)
+	if depth+1 > maxIncludeDepth {
+		return nil, "", errors.WithStackTrace(TooManyLevelsOfInheritance{
+			ConfigPath: configPath,
+			MaxDepth:   maxIncludeDepth,
+		})
+	}
+
+	config, err := parseConfigFile(resolvedIncludePath, terragruntOptions, includedConfig, visited, depth+1)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return config, absIncludePath, nil
 }
 
 // Convert the contents of a fully resolved Terragrunt configuration to a TerragruntConfig object
@@ -339,6 +896,15 @@ func convertToTerragruntConfig(terragruntConfigFromFile *terragruntConfigFile, t
 	return terragruntConfig, nil
 }
 
+// newPlainTerraformConfig builds the TerragruntConfig used for a directory detected by
+// PlainTerraformConfigFileDetector: no remote_state, no include, and a source-less TerraformConfig, so the
+// directory's own *.tf files are what get applied.
+func newPlainTerraformConfig() *TerragruntConfig {
+	return &TerragruntConfig{
+		Terraform: &TerraformConfig{},
+	}
+}
+
 // Custom error types
 
 type IncludedConfigMissingPath string
@@ -348,13 +914,23 @@ func (err IncludedConfigMissingPath) Error() string {
 }
 
 type TooManyLevelsOfInheritance struct {
-	ConfigPath             string
-	FirstLevelIncludePath  string
-	SecondLevelIncludePath string
+	ConfigPath string
+	MaxDepth   int
 }
 
 func (err TooManyLevelsOfInheritance) Error() string {
-	return fmt.Sprintf("%s includes %s, which itself includes %s. Only one level of includes is allowed.", err.ConfigPath, err.FirstLevelIncludePath, err.SecondLevelIncludePath)
+	return fmt.Sprintf("%s is nested more than %d levels deep via include chains. Raise TerragruntOptions.MaxIncludeDepth if this is intentional.", err.ConfigPath, err.MaxDepth)
+}
+
+// CircularInclude is returned when an include chain loops back to a config it has already parsed, e.g. A includes B
+// and B includes A.
+type CircularInclude struct {
+	ConfigPath  string
+	IncludePath string
+}
+
+func (err CircularInclude) Error() string {
+	return fmt.Sprintf("%s includes %s, which creates a circular chain of includes", err.ConfigPath, err.IncludePath)
 }
 
 type CouldNotResolveTerragruntConfigInFile string
@@ -0,0 +1,358 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terragrunt/options"
+	"github.com/gruntwork-io/terragrunt/remote"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeTestFile writes contents to name inside dir and returns the full path.
+func writeTestFile(t *testing.T, dir string, name string, contents string) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatalf("failed to write test fixture %s: %s", path, err)
+	}
+	return path
+}
+
+func TestContainsTerragruntBlockHCLAndJSON(t *testing.T) {
+	t.Parallel()
+
+	hcl := `
+terragrunt = {
+  terraform {
+    source = "git::git@github.com:foo/bar.git"
+  }
+}
+`
+	json := `{"terragrunt": {"terraform": {"source": "git::git@github.com:foo/bar.git"}}}`
+
+	assert.True(t, containsTerragruntBlock(hcl), "HCL terragrunt block should be detected")
+	assert.True(t, containsTerragruntBlock(json), "JSON terragrunt block should be detected")
+	assert.False(t, containsTerragruntBlock(`{"not_terragrunt": true}`), "a JSON file with no terragrunt block should not be detected")
+}
+
+// TestParseConfigStringAsTerragruntConfigFileHCLAndJSONRoundTrip pins the core of chunk0-1: equivalent HCL and JSON
+// input must decode to the same terragruntConfigFile, since both go through the same hcl.Decode call.
+func TestParseConfigStringAsTerragruntConfigFileHCLAndJSONRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	hclConfig := `
+terragrunt = {
+  terraform {
+    source = "git::git@github.com:foo/bar.git"
+  }
+
+  dependencies {
+    paths = ["../vpc", "../mysql"]
+  }
+}
+`
+	jsonConfig := `{
+  "terragrunt": {
+    "terraform": {
+      "source": "git::git@github.com:foo/bar.git"
+    },
+    "dependencies": {
+      "paths": ["../vpc", "../mysql"]
+    }
+  }
+}`
+
+	hclParsed, err := parseConfigStringAsTerragruntConfigFile(hclConfig, "terraform.tfvars")
+	assert.NoError(t, err)
+
+	jsonParsed, err := parseConfigStringAsTerragruntConfigFile(jsonConfig, "terragrunt.json")
+	assert.NoError(t, err)
+
+	assert.Equal(t, hclParsed.Terraform.Source, jsonParsed.Terraform.Source)
+	assert.Equal(t, hclParsed.Dependencies.Paths, jsonParsed.Dependencies.Paths)
+}
+
+// TestParseConfigFileJSONInterpolation exercises the full ParseConfigFile pipeline (ResolveTerragruntConfigString
+// followed by JSON decoding) to confirm interpolations still run on string fields inside a terragrunt.json file, not
+// just HCL ones, per the chunk0-1 request.
+func TestParseConfigFileJSONInterpolation(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, err := ioutil.TempDir("", "terragrunt-json-interpolation")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	os.Setenv("TERRAGRUNT_TEST_SOURCE_PREFIX", "git::git@github.com:foo/bar.git")
+	defer os.Unsetenv("TERRAGRUNT_TEST_SOURCE_PREFIX")
+
+	configPath := writeTestFile(t, tmpDir, DefaultTerragruntJSONConfigPath, `{
+  "terragrunt": {
+    "terraform": {
+      "source": "${get_env(\"TERRAGRUNT_TEST_SOURCE_PREFIX\", \"\")}"
+    }
+  }
+}`)
+
+	terragruntOptions := options.NewTerragruntOptions(configPath)
+	config, err := ParseConfigFile(configPath, terragruntOptions, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "git::git@github.com:foo/bar.git", config.Terraform.Source)
+}
+
+// TestDiscoveryFilterPrefixMatchesPathSegments pins the chunk0-2 review fix: a `prefix = "live"` rule must match only
+// at a path-segment boundary, not any path that merely starts with the string "live".
+func TestDiscoveryFilterPrefixMatchesPathSegments(t *testing.T) {
+	t.Parallel()
+
+	filter, err := compileDiscoveryFilter(&discoveryFilterFile{
+		Rules: []discoveryRule{{Prefix: "live", Exclude: true}},
+	})
+	assert.NoError(t, err)
+
+	assert.False(t, filter.included("live/prod/vpc"), "a path under the prefix should be excluded")
+	assert.True(t, filter.included("live-old/prod/vpc"), "a path that merely starts with the prefix string should not match")
+	assert.True(t, filter.included("livestock/vpc"), "a sibling directory sharing the prefix as a substring should not match")
+}
+
+// TestPlainTerraformConfigFileDetectorIsConfigFile pins the chunk0-3 review fix: IsConfigFile must not use
+// filepath.Glob, since that treats glob metacharacters anywhere in the directory path, not just the "*.tf" pattern,
+// as pattern syntax: it errors out on an unmatched '[', and reads a directory name like "module[wip]" as a character
+// class, so the directory's own main.tf is never found.
+func TestPlainTerraformConfigFileDetectorIsConfigFile(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, err := ioutil.TempDir("", "plain-terraform-detector")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	detector := &PlainTerraformConfigFileDetector{}
+
+	moduleDir := filepath.Join(tmpDir, "module[wip]")
+	if err := os.MkdirAll(moduleDir, 0755); err != nil {
+		t.Fatalf("failed to create %s: %s", moduleDir, err)
+	}
+	writeTestFile(t, moduleDir, "main.tf", "# empty")
+
+	isConfig, err := detector.IsConfigFile(moduleDir)
+	assert.NoError(t, err, "a directory name containing glob metacharacters must not produce an error")
+	assert.True(t, isConfig, "a directory containing a .tf file should be recognized")
+
+	emptyDir := filepath.Join(tmpDir, "empty")
+	if err := os.MkdirAll(emptyDir, 0755); err != nil {
+		t.Fatalf("failed to create %s: %s", emptyDir, err)
+	}
+	isConfig, err = detector.IsConfigFile(emptyDir)
+	assert.NoError(t, err)
+	assert.False(t, isConfig, "a directory with no .tf files should not be recognized")
+}
+
+func TestConfigFileDetectorsForOptionsIncludesPlainTerraformWhenOptedIn(t *testing.T) {
+	t.Parallel()
+
+	withoutOptIn := ConfigFileDetectorsForOptions(options.NewTerragruntOptions("terraform.tfvars"))
+	assert.Len(t, withoutOptIn, len(DefaultConfigFileDetectors()), "without the opt-in, only the default detectors should be used")
+
+	terragruntOptions := options.NewTerragruntOptions("terraform.tfvars")
+	terragruntOptions.IncludePlainTerraformModules = true
+	withOptIn := ConfigFileDetectorsForOptions(terragruntOptions)
+	assert.Len(t, withOptIn, len(DefaultConfigFileDetectors())+1, "the opt-in should append exactly one more detector")
+
+	_, isPlainDetector := withOptIn[len(withOptIn)-1].(*PlainTerraformConfigFileDetector)
+	assert.True(t, isPlainDetector, "the opt-in detector should be appended last")
+}
+
+// TestFindConfigFilesInPathWithPlainTerraformModulesSkipsOddlyNamedDirsWithoutError is the end-to-end regression
+// for the same chunk0-3 Glob bug: a directory name containing glob metacharacters, such as "module[wip]", must
+// neither make discovery fail nor be missed by it, and must not affect the directories around it.
+func TestFindConfigFilesInPathWithPlainTerraformModulesSkipsOddlyNamedDirsWithoutError(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, err := ioutil.TempDir("", "find-config-files-plain-terraform")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	oddDir := filepath.Join(tmpDir, "module[wip]")
+	if err := os.MkdirAll(oddDir, 0755); err != nil {
+		t.Fatalf("failed to create %s: %s", oddDir, err)
+	}
+	writeTestFile(t, oddDir, "main.tf", "# empty")
+
+	plainDir := filepath.Join(tmpDir, "plain-module")
+	if err := os.MkdirAll(plainDir, 0755); err != nil {
+		t.Fatalf("failed to create %s: %s", plainDir, err)
+	}
+	writeTestFile(t, plainDir, "main.tf", "# empty")
+
+	terragruntOptions := options.NewTerragruntOptions(filepath.Join(tmpDir, "terraform.tfvars"))
+	terragruntOptions.IncludePlainTerraformModules = true
+
+	configFiles, err := FindConfigFilesInPath(tmpDir, ConfigFileDetectorsForOptions(terragruntOptions)...)
+	assert.NoError(t, err, "an oddly-named directory elsewhere in the tree should not abort the whole walk")
+	assert.Contains(t, configFiles, oddDir)
+	assert.Contains(t, configFiles, plainDir)
+}
+
+func TestMergeRemoteStatePerFieldOverride(t *testing.T) {
+	t.Parallel()
+
+	parent := &remote.RemoteState{Backend: "s3", Config: map[string]interface{}{"bucket": "parent-bucket", "region": "us-east-1"}}
+	child := &remote.RemoteState{Config: map[string]interface{}{"bucket": "child-bucket"}}
+
+	merged := mergeRemoteState(parent, child)
+
+	assert.Equal(t, "s3", merged.Backend, "backend should be inherited when the child doesn't set one")
+	assert.Equal(t, "child-bucket", merged.Config["bucket"], "child should override just the bucket")
+	assert.Equal(t, "us-east-1", merged.Config["region"], "region should still be inherited from the parent")
+}
+
+// TestMergeConfigWithIncludedConfigTracksRemoteStateProvenancePerKey pins the review fix for a provenance bug: when a
+// child only overrides one RemoteState.Config key, the keys it left inherited must keep the parent's provenance,
+// not get silently re-attributed to the child just because the child also set a RemoteState block.
+func TestMergeConfigWithIncludedConfigTracksRemoteStateProvenancePerKey(t *testing.T) {
+	t.Parallel()
+
+	orgConfigPath := "org/terraform.tfvars"
+	orgConfig := &TerragruntConfig{
+		RemoteState: &remote.RemoteState{Backend: "s3", Config: map[string]interface{}{"bucket": "org-bucket", "region": "us-east-1"}},
+	}
+	orgConfig.Provenance = provenanceForLeaf(orgConfigPath, orgConfig)
+
+	appConfigPath := "app/terraform.tfvars"
+	appConfig := &TerragruntConfig{
+		RemoteState: &remote.RemoteState{Config: map[string]interface{}{"bucket": "app-bucket"}},
+	}
+
+	merged, err := mergeConfigWithIncludedConfig(appConfigPath, appConfig, orgConfig, options.NewTerragruntOptions(appConfigPath))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "app-bucket", merged.RemoteState.Config["bucket"])
+	assert.Equal(t, "us-east-1", merged.RemoteState.Config["region"])
+
+	assert.Equal(t, appConfigPath, merged.ProvenanceFor("RemoteState.Config.bucket"), "the overridden key should be attributed to the child that overrode it")
+	assert.Equal(t, orgConfigPath, merged.ProvenanceFor("RemoteState.Config.region"), "the inherited key should still be attributed to the org config, not the child that merely overrode a sibling key")
+}
+
+func TestMergeDependenciesAppendAndReplace(t *testing.T) {
+	t.Parallel()
+
+	parent := &ModuleDependencies{Paths: []string{"../vpc"}}
+
+	replaced := mergeDependencies(nil, parent, &ModuleDependencies{Paths: []string{"../mysql"}})
+	assert.Equal(t, []string{"../mysql"}, replaced.Paths, "default merge mode should replace the parent's paths")
+
+	appended := mergeDependencies(nil, parent, &ModuleDependencies{Paths: []string{"../mysql"}, Merge: DependenciesMergeAppend})
+	assert.Equal(t, []string{"../vpc", "../mysql"}, appended.Paths, "merge = \"append\" should keep the parent's paths and append the child's")
+}
+
+// TestRemoveExtraArgsRunsBeforeMergePreservesChildsOwnBlock pins the ordering fix from the chunk0-4 review: removing
+// a parent's extra_arguments block by name must not also delete a same-named block the child defines itself.
+func TestRemoveExtraArgsRunsBeforeMergePreservesChildsOwnBlock(t *testing.T) {
+	t.Parallel()
+
+	parentArgs := []TerraformExtraArguments{{Name: "foo", Arguments: []string{"-parent"}}}
+	removeExtraArgs([]string{"foo"}, &parentArgs)
+	assert.Empty(t, parentArgs, "removeExtraArgs should drop the inherited 'foo' block")
+
+	childArgs := []TerraformExtraArguments{{Name: "foo", Arguments: []string{"-child"}}}
+	mergeExtraArgs(options.NewTerragruntOptions("child.tfvars"), childArgs, &parentArgs)
+	assert.Equal(t, childArgs, parentArgs, "the child's own 'foo' block, added after the removal, must survive")
+}
+
+// TestParseConfigFileMultiLevelIncludeResolvesRelativeToImmediateParent pins the regression flagged in review: a
+// middle-of-the-chain config's relative include path must resolve against its own directory, not the root config's.
+func TestParseConfigFileMultiLevelIncludeResolvesRelativeToImmediateParent(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, err := ioutil.TempDir("", "terragrunt-multi-level-include")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	appDir := filepath.Join(tmpDir, "app")
+	teamDir := filepath.Join(tmpDir, "team")
+	orgDir := filepath.Join(tmpDir, "org")
+	for _, dir := range []string{appDir, teamDir, orgDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("failed to create %s: %s", dir, err)
+		}
+	}
+
+	writeTestFile(t, orgDir, DefaultTerragruntConfigPath, `
+terragrunt = {
+  dependencies {
+    paths = ["../org-module"]
+  }
+}
+`)
+	writeTestFile(t, teamDir, DefaultTerragruntConfigPath, `
+terragrunt = {
+  include {
+    path = "../org/terraform.tfvars"
+  }
+}
+`)
+	appConfigPath := writeTestFile(t, appDir, DefaultTerragruntConfigPath, `
+terragrunt = {
+  include {
+    path = "../team/terraform.tfvars"
+  }
+}
+`)
+
+	terragruntOptions := options.NewTerragruntOptions(appConfigPath)
+	config, err := ParseConfigFile(appConfigPath, terragruntOptions, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"../org-module"}, config.Dependencies.Paths, "dependencies should be inherited all the way from the org-level config")
+
+	teamAbsPath, _ := filepath.Abs(filepath.Join(teamDir, DefaultTerragruntConfigPath))
+	orgAbsPath, _ := filepath.Abs(filepath.Join(orgDir, DefaultTerragruntConfigPath))
+	assert.Equal(t, []string{teamAbsPath, orgAbsPath}, config.IncludedConfigPaths, "the chain should be ordered from the immediate parent to the root")
+}
+
+func TestParseConfigFileCircularIncludeDetected(t *testing.T) {
+	t.Parallel()
+
+	tmpDir, err := ioutil.TempDir("", "terragrunt-circular-include")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	aDir := filepath.Join(tmpDir, "a")
+	bDir := filepath.Join(tmpDir, "b")
+	for _, dir := range []string{aDir, bDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("failed to create %s: %s", dir, err)
+		}
+	}
+
+	writeTestFile(t, aDir, DefaultTerragruntConfigPath, `
+terragrunt = {
+  include {
+    path = "../b/terraform.tfvars"
+  }
+}
+`)
+	bConfigPath := writeTestFile(t, bDir, DefaultTerragruntConfigPath, `
+terragrunt = {
+  include {
+    path = "../a/terraform.tfvars"
+  }
+}
+`)
+
+	terragruntOptions := options.NewTerragruntOptions(bConfigPath)
+	_, err = ParseConfigFile(bConfigPath, terragruntOptions, nil)
+	assert.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "circular"), "expected a circular include error, got %v", err)
+}
@@ -0,0 +1,45 @@
+package options
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// TerragruntOptions represents the configuration options provided by the user via CLI options and environment
+// variables
+type TerragruntOptions struct {
+	// TerragruntConfigPath is the path to the Terragrunt config file to read
+	TerragruntConfigPath string
+
+	// WorkingDir is the directory in which Terragrunt runs Terraform commands
+	WorkingDir string
+
+	// Logger is the logger to use for all Terragrunt log messages
+	Logger *log.Logger
+
+	// IncludePlainTerraformModules, when true, makes config.FindConfigFilesInPath additionally treat directories
+	// that contain plain *.tf files (but no Terragrunt config of their own) as discoverable modules, via
+	// config.PlainTerraformConfigFileDetector. It's consulted by config.ConfigFileDetectorsForOptions; this package
+	// has no CLI surface of its own, so a caller (e.g. a --terragrunt-include-plain-modules flag) is what sets it.
+	IncludePlainTerraformModules bool
+
+	// MaxIncludeDepth bounds how many levels of include chains config.ParseConfigFile will follow for this parse
+	// before giving up with config.TooManyLevelsOfInheritance. It lives here, per-TerragruntOptions, rather than as
+	// global state, so that run-all/plan-all/apply-all can parse many module configs in the same process without
+	// one module's override racing another's.
+	MaxIncludeDepth int
+}
+
+// DefaultMaxIncludeDepth is the MaxIncludeDepth every TerragruntOptions gets unless a caller overrides it.
+const DefaultMaxIncludeDepth = 8
+
+// NewTerragruntOptions returns a new TerragruntOptions with defaults for everything but the config path.
+func NewTerragruntOptions(terragruntConfigPath string) *TerragruntOptions {
+	return &TerragruntOptions{
+		TerragruntConfigPath: terragruntConfigPath,
+		WorkingDir:           filepath.Dir(terragruntConfigPath),
+		Logger:               log.New(os.Stderr, "", log.LstdFlags),
+		MaxIncludeDepth:      DefaultMaxIncludeDepth,
+	}
+}